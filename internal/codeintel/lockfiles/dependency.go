@@ -0,0 +1,20 @@
+package lockfiles
+
+import "fmt"
+
+// genericDependency is a reposource.PackageDependency for ecosystems that
+// don't otherwise need their own concrete type: a package manager scheme
+// plus the name and resolved version pinned by the lockfile.
+type genericDependency struct {
+	scheme  string
+	name    string
+	version string
+}
+
+func (d *genericDependency) Scheme() string         { return d.scheme }
+func (d *genericDependency) PackageSyntax() string  { return d.name }
+func (d *genericDependency) PackageVersion() string { return d.version }
+
+func (d *genericDependency) PackageManagerSyntax() string {
+	return fmt.Sprintf("%s@%s", d.name, d.version)
+}
@@ -0,0 +1,137 @@
+package lockfiles
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+)
+
+var gitmodulesParser = treeParser{
+	pathspecs: []string{".gitmodules", "**/.gitmodules"},
+	matches:   basenameIs(".gitmodules"),
+	parse:     parseGitmodules,
+}
+
+// gitSubmodule is a single `[submodule "name"]` section of a .gitmodules
+// file.
+type gitSubmodule struct {
+	name   string
+	path   string
+	url    string
+	branch string
+}
+
+// parseGitmodules decodes a .gitmodules file and resolves each submodule's
+// path to the gitlink object id recorded in the tree, yielding a
+// GitSubmoduleDependency pinned at that commit.
+func parseGitmodules(ctx context.Context, gitSvc GitService, repo api.RepoName, commit api.CommitID, content []byte) ([]reposource.PackageDependency, error) {
+	submodules, err := decodeGitmodules(content)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid .gitmodules")
+	}
+
+	deps := make([]reposource.PackageDependency, 0, len(submodules))
+	for _, sm := range submodules {
+		if sm.path == "" || sm.url == "" {
+			continue
+		}
+
+		oid, err := gitSvc.GitLinkOID(ctx, repo, commit, sm.path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "resolving submodule %q at %q", sm.name, sm.path)
+		}
+
+		deps = append(deps, NewGitSubmoduleDependency(sm.url, oid))
+	}
+
+	return deps, nil
+}
+
+// decodeGitmodules parses the INI-like format of a .gitmodules file into
+// its submodule sections. Only the keys lockfiles cares about (path, url,
+// branch) are extracted.
+func decodeGitmodules(content []byte) ([]gitSubmodule, error) {
+	var submodules []gitSubmodule
+
+	var current *gitSubmodule
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[submodule ") {
+			submodules = append(submodules, gitSubmodule{name: parseSectionName(line)})
+			current = &submodules[len(submodules)-1]
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		key, value, ok := parseGitmodulesKV(line)
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "path":
+			current.path = value
+		case "url":
+			current.url = value
+		case "branch":
+			current.branch = value
+		}
+	}
+
+	return submodules, scanner.Err()
+}
+
+// parseSectionName extracts "name" out of a `[submodule "name"]` header.
+func parseSectionName(line string) string {
+	open := strings.IndexByte(line, '"')
+	shut := strings.LastIndexByte(line, '"')
+	if open < 0 || shut <= open {
+		return ""
+	}
+	return line[open+1 : shut]
+}
+
+func parseGitmodulesKV(line string) (key, value string, ok bool) {
+	idx := strings.IndexByte(line, '=')
+	if idx < 0 {
+		return "", "", false
+	}
+	return strings.TrimSpace(line[:idx]), strings.TrimSpace(line[idx+1:]), true
+}
+
+// GitSubmoduleDependency represents a vendored git repository pinned via a
+// gitlink entry in a .gitmodules-managed submodule, rather than a package
+// manager manifest.
+type GitSubmoduleDependency struct {
+	url string
+	oid api.CommitID
+}
+
+// NewGitSubmoduleDependency returns a GitSubmoduleDependency for the
+// submodule remote url pinned at oid.
+func NewGitSubmoduleDependency(url string, oid api.CommitID) *GitSubmoduleDependency {
+	return &GitSubmoduleDependency{url: url, oid: oid}
+}
+
+func (d *GitSubmoduleDependency) Scheme() string         { return "gitmodules" }
+func (d *GitSubmoduleDependency) PackageSyntax() string  { return d.url }
+func (d *GitSubmoduleDependency) PackageVersion() string { return string(d.oid) }
+
+func (d *GitSubmoduleDependency) PackageManagerSyntax() string {
+	return fmt.Sprintf("%s@%s", d.url, d.oid)
+}
@@ -0,0 +1,47 @@
+package lockfiles
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+)
+
+var goModParser = parser{
+	pathspecs: []string{"**/go.sum"},
+	matches:   basenameIs("go.sum"),
+	parse:     parseGoSum,
+}
+
+// parseGoSum extracts module versions out of a go.sum file. Each module
+// appears on two lines (one for the module zip hash, one for its go.mod
+// hash); we only need the version once per module, and we skip the
+// "/go.mod" hash lines entirely since they pin the same version.
+func parseGoSum(content []byte) ([]reposource.PackageDependency, error) {
+	seen := make(map[string]struct{})
+	var deps []reposource.PackageDependency
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+
+		module, version := fields[0], fields[1]
+		if strings.HasSuffix(version, "/go.mod") {
+			continue
+		}
+
+		key := module + "@" + version
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		deps = append(deps, &genericDependency{scheme: "go", name: module, version: version})
+	}
+
+	return deps, scanner.Err()
+}
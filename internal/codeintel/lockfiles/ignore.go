@@ -0,0 +1,287 @@
+package lockfiles
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// ignoreFilter drops candidate lockfile paths that are vendored third-party
+// code rather than first-party dependencies: paths excluded by a
+// .gitignore, or tagged linguist-vendored/linguist-generated by a
+// .gitattributes.
+type ignoreFilter struct {
+	gitignoreRules    []gitignoreRule
+	gitattributeRules []gitattributeRule
+}
+
+// filter returns paths with every entry matched by the filter removed.
+func (f *ignoreFilter) filter(paths []string) []string {
+	if f == nil {
+		return paths
+	}
+
+	out := paths[:0:0]
+	for _, p := range paths {
+		if f.isIgnored(p) {
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func (f *ignoreFilter) isIgnored(filePath string) bool {
+	ignored := false
+	for _, r := range f.gitignoreRules {
+		if r.matches(filePath) {
+			ignored = !r.negate
+		}
+	}
+	if ignored {
+		return true
+	}
+
+	var vendored, generated triState
+	for _, r := range f.gitattributeRules {
+		if !r.matches(filePath) {
+			continue
+		}
+		if r.vendored != unspecified {
+			vendored = r.vendored
+		}
+		if r.generated != unspecified {
+			generated = r.generated
+		}
+	}
+	return vendored == set || generated == set
+}
+
+// newIgnoreFilter builds an ignoreFilter out of the .gitignore and
+// .gitattributes blobs found in a repository, keyed by the directory
+// (slash-separated, "" for the repository root) each file lives in.
+func newIgnoreFilter(gitignores, gitattributes map[string][]byte) *ignoreFilter {
+	f := &ignoreFilter{}
+
+	for _, dir := range sortedDirsByDepth(gitignores) {
+		f.gitignoreRules = append(f.gitignoreRules, parseGitignore(dir, gitignores[dir])...)
+	}
+	for _, dir := range sortedDirsByDepth(gitattributes) {
+		f.gitattributeRules = append(f.gitattributeRules, parseGitattributes(dir, gitattributes[dir])...)
+	}
+
+	return f
+}
+
+// sortedDirsByDepth returns the keys of dirs (directory paths) ordered
+// shallowest-first, so that rules from a repository's root are applied
+// before rules from a more specific subdirectory and can be overridden by
+// them, matching git's own precedence.
+func sortedDirsByDepth(dirs map[string][]byte) []string {
+	out := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		out = append(out, dir)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		di, dj := strings.Count(out[i], "/"), strings.Count(out[j], "/")
+		if di != dj {
+			return di < dj
+		}
+		return out[i] < out[j]
+	})
+	return out
+}
+
+// gitignoreRule is a single non-comment, non-blank line of a .gitignore,
+// scoped to the directory the .gitignore file was found in.
+type gitignoreRule struct {
+	dir      string
+	pattern  string
+	negate   bool
+	anchored bool
+	dirOnly  bool
+}
+
+func parseGitignore(dir string, content []byte) []gitignoreRule {
+	var rules []gitignoreRule
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := gitignoreRule{dir: dir}
+
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasPrefix(line, "/") {
+			rule.anchored = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+
+		if line == "" {
+			continue
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+
+	return rules
+}
+
+// matches reports whether filePath (slash-separated, relative to the
+// repository root) is excluded by this rule, either directly or because
+// one of its ancestor directories is.
+func (r gitignoreRule) matches(filePath string) bool {
+	if r.dir != "" {
+		if !strings.HasPrefix(filePath, r.dir+"/") {
+			return false
+		}
+		filePath = strings.TrimPrefix(filePath, r.dir+"/")
+	}
+
+	segments := strings.Split(filePath, "/")
+	for i := 1; i <= len(segments); i++ {
+		isFile := i == len(segments)
+		if r.dirOnly && isFile {
+			// A dirOnly pattern ("vendor/") never matches the file
+			// itself, only a directory above it.
+			continue
+		}
+
+		if globMatch(r.pattern, r.anchored, strings.Join(segments[:i], "/")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// triState represents the value of a single boolean gitattributes
+// attribute (e.g. linguist-vendored) as it appears on one line: present and
+// true (set), present and negated with "-" (unset), or absent from that
+// line entirely (unspecified, meaning a less specific rule's value, if
+// any, carries through unchanged).
+type triState int
+
+const (
+	unspecified triState = iota
+	set
+	unset
+)
+
+// gitattributeRule is a single pattern's worth of linguist attributes from
+// a .gitattributes file, scoped to the directory it was found in. vendored
+// and generated are tracked independently so that a line negating one
+// attribute (e.g. "-linguist-generated") can't also clear the other.
+type gitattributeRule struct {
+	dir       string
+	pattern   string
+	anchored  bool
+	vendored  triState
+	generated triState
+}
+
+func parseGitattributes(dir string, content []byte) []gitattributeRule {
+	var rules []gitattributeRule
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimRight(line, "\r")
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		pattern := fields[0]
+		var vendored, generated triState
+		for _, attr := range fields[1:] {
+			switch attr {
+			case "linguist-vendored":
+				vendored = set
+			case "-linguist-vendored":
+				vendored = unset
+			case "linguist-generated":
+				generated = set
+			case "-linguist-generated":
+				generated = unset
+			}
+		}
+		if vendored == unspecified && generated == unspecified {
+			continue
+		}
+
+		rules = append(rules, newGitattributeRule(dir, pattern, vendored, generated))
+	}
+
+	return rules
+}
+
+func newGitattributeRule(dir, pattern string, vendored, generated triState) gitattributeRule {
+	rule := gitattributeRule{dir: dir, pattern: pattern, vendored: vendored, generated: generated}
+	if strings.HasPrefix(pattern, "/") {
+		rule.anchored = true
+		rule.pattern = pattern[1:]
+	}
+	return rule
+}
+
+func (r gitattributeRule) matches(filePath string) bool {
+	if r.dir != "" {
+		if !strings.HasPrefix(filePath, r.dir+"/") {
+			return false
+		}
+		filePath = strings.TrimPrefix(filePath, r.dir+"/")
+	}
+
+	return globMatch(r.pattern, r.anchored, filePath) || globMatch(r.pattern, r.anchored, path.Base(filePath))
+}
+
+// globMatch reports whether name matches pattern, which may use the
+// gitignore extensions "**" (match any number of path components) on top
+// of ordinary shell glob syntax. If anchored is false and pattern contains
+// no slash, it's matched against name's base component rather than the
+// full path, mirroring gitignore's "a pattern without a slash matches
+// anywhere" rule.
+func globMatch(pattern string, anchored bool, name string) bool {
+	if !anchored && !strings.Contains(pattern, "/") {
+		name = path.Base(name)
+	}
+
+	return doubleStarMatch(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func doubleStarMatch(patSegs, nameSegs []string) bool {
+	if len(patSegs) == 0 {
+		return len(nameSegs) == 0
+	}
+
+	if patSegs[0] == "**" {
+		if doubleStarMatch(patSegs[1:], nameSegs) {
+			return true
+		}
+		if len(nameSegs) == 0 {
+			return false
+		}
+		return doubleStarMatch(patSegs, nameSegs[1:])
+	}
+
+	if len(nameSegs) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(patSegs[0], nameSegs[0]); !ok {
+		return false
+	}
+	return doubleStarMatch(patSegs[1:], nameSegs[1:])
+}
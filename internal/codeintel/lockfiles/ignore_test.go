@@ -0,0 +1,58 @@
+package lockfiles
+
+import "testing"
+
+func TestIgnoreFilter(t *testing.T) {
+	gitignores := map[string][]byte{
+		"": []byte("node_modules/\n!node_modules/keep-me\n*.generated.json\n"),
+	}
+	gitattributes := map[string][]byte{
+		// linguist-vendored and -linguist-generated on the same line must
+		// be tracked independently: negating "generated" shouldn't also
+		// clear the "vendored" match on the same path.
+		"": []byte("vendor/** linguist-vendored -linguist-generated\nthird_party/**/go.sum linguist-generated\n"),
+	}
+
+	f := newIgnoreFilter(gitignores, gitattributes)
+
+	tests := []struct {
+		path    string
+		ignored bool
+	}{
+		{"package-lock.json", false},
+		{"node_modules/left-pad/package-lock.json", true},
+		{"node_modules/keep-me/package-lock.json", false},
+		{"build/output.generated.json", true},
+		{"vendor/github.com/foo/bar/go.sum", true},
+		{"third_party/baz/go.sum", true},
+		{"src/go.sum", false},
+	}
+
+	for _, test := range tests {
+		if got := f.isIgnored(test.path); got != test.ignored {
+			t.Errorf("isIgnored(%q) = %v, want %v", test.path, got, test.ignored)
+		}
+	}
+}
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		anchored bool
+		name     string
+		want     bool
+	}{
+		{"*.lock", false, "yarn.lock", true},
+		{"*.lock", false, "nested/yarn.lock", true},
+		{"build", true, "build", true},
+		{"build", true, "nested/build", false},
+		{"vendor/**", false, "vendor/a/b/c", true},
+		{"vendor/**", false, "other/a", false},
+	}
+
+	for _, test := range tests {
+		if got := globMatch(test.pattern, test.anchored, test.name); got != test.want {
+			t.Errorf("globMatch(%q, %v, %q) = %v, want %v", test.pattern, test.anchored, test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package lockfiles
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+)
+
+var npmParser = parser{
+	pathspecs: []string{"**/package-lock.json"},
+	matches:   basenameIs("package-lock.json"),
+	parse:     parseNpmLockfile,
+}
+
+var yarnParser = parser{
+	pathspecs: []string{"**/yarn.lock"},
+	matches:   basenameIs("yarn.lock"),
+	parse:     parseYarnLockfile,
+}
+
+var pnpmParser = parser{
+	pathspecs: []string{"**/pnpm-lock.yaml"},
+	matches:   basenameIs("pnpm-lock.yaml"),
+	parse:     parsePnpmLockfile,
+}
+
+// packageLockJSON is the subset of npm's package-lock.json we care about:
+// the resolved version of each (possibly transitive) dependency.
+type packageLockJSON struct {
+	Dependencies map[string]struct {
+		Version string `json:"version"`
+	} `json:"dependencies"`
+}
+
+func parseNpmLockfile(content []byte) ([]reposource.PackageDependency, error) {
+	var lockfile packageLockJSON
+	if err := json.Unmarshal(content, &lockfile); err != nil {
+		return nil, errors.Wrap(err, "invalid package-lock.json")
+	}
+
+	deps := make([]reposource.PackageDependency, 0, len(lockfile.Dependencies))
+	for name, pkg := range lockfile.Dependencies {
+		dep, err := reposource.ParseNPMDependency(fmt.Sprintf("%s@%s", name, pkg.Version))
+		if err != nil {
+			return nil, err
+		}
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
+
+// parseYarnLockfile extracts resolved versions out of a yarn.lock file.
+// Entries look like:
+//
+//	"@octokit/request@^5.0.0":
+//	  version "5.6.2"
+//	  resolved "..."
+func parseYarnLockfile(content []byte) ([]reposource.PackageDependency, error) {
+	var deps []reposource.PackageDependency
+
+	var name string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case len(line) > 0 && line[0] != ' ' && line[0] != '#' && strings.HasSuffix(strings.TrimSpace(line), ":"):
+			name = yarnEntryName(line)
+		case strings.HasPrefix(strings.TrimSpace(line), "version "):
+			if name == "" {
+				continue
+			}
+			version := strings.Trim(strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "version")), `" `)
+			dep, err := reposource.ParseNPMDependency(fmt.Sprintf("%s@%s", name, version))
+			if err != nil {
+				return nil, err
+			}
+			deps = append(deps, dep)
+			name = ""
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "scanning yarn.lock")
+	}
+
+	return deps, nil
+}
+
+// yarnEntryName extracts the package name out of a yarn.lock entry header,
+// which may list several comma-separated version specifiers for the same
+// package, e.g. `"lodash@^4.0.0", "lodash@^4.1.0":`.
+func yarnEntryName(line string) string {
+	first := strings.TrimSpace(strings.Split(line, ",")[0])
+	first = strings.Trim(first, `":`)
+
+	if idx := strings.LastIndex(first, "@"); idx > 0 {
+		return first[:idx]
+	}
+	return first
+}
+
+// pnpmLockfile is the subset of pnpm-lock.yaml we care about: the packages
+// section, whose keys are "/name/version" (or "/@scope/name/version").
+type pnpmLockfile struct {
+	Packages map[string]interface{} `yaml:"packages"`
+}
+
+func parsePnpmLockfile(content []byte) ([]reposource.PackageDependency, error) {
+	var lockfile pnpmLockfile
+	if err := yaml.Unmarshal(content, &lockfile); err != nil {
+		return nil, errors.Wrap(err, "invalid pnpm-lock.yaml")
+	}
+
+	deps := make([]reposource.PackageDependency, 0, len(lockfile.Packages))
+	for key := range lockfile.Packages {
+		name, version, ok := splitPnpmKey(key)
+		if !ok {
+			continue
+		}
+		dep, err := reposource.ParseNPMDependency(fmt.Sprintf("%s@%s", name, version))
+		if err != nil {
+			return nil, err
+		}
+		deps = append(deps, dep)
+	}
+	return deps, nil
+}
+
+// splitPnpmKey splits a pnpm-lock.yaml package key of the form
+// "/name/version" or "/@scope/name/version" into its name and version.
+func splitPnpmKey(key string) (name, version string, ok bool) {
+	key = strings.TrimPrefix(key, "/")
+	idx := strings.LastIndex(key, "/")
+	if idx < 0 {
+		return "", "", false
+	}
+	return key[:idx], key[idx+1:], true
+}
@@ -0,0 +1,40 @@
+package lockfiles
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+)
+
+var phpParser = parser{
+	pathspecs: []string{"**/composer.lock"},
+	matches:   basenameIs("composer.lock"),
+	parse:     parseComposerLock,
+}
+
+// composerLock is the subset of composer.lock we care about: the resolved
+// name and version of every production and development package.
+type composerLock struct {
+	Packages    []composerPackage `json:"packages"`
+	PackagesDev []composerPackage `json:"packages-dev"`
+}
+
+type composerPackage struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+func parseComposerLock(content []byte) ([]reposource.PackageDependency, error) {
+	var lockfile composerLock
+	if err := json.Unmarshal(content, &lockfile); err != nil {
+		return nil, errors.Wrap(err, "invalid composer.lock")
+	}
+
+	deps := make([]reposource.PackageDependency, 0, len(lockfile.Packages)+len(lockfile.PackagesDev))
+	for _, pkg := range append(lockfile.Packages, lockfile.PackagesDev...) {
+		deps = append(deps, &genericDependency{scheme: "composer", name: pkg.Name, version: pkg.Version})
+	}
+	return deps, nil
+}
@@ -0,0 +1,124 @@
+package lockfiles
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+)
+
+var pythonParser = parser{
+	pathspecs: []string{"**/poetry.lock", "**/Pipfile.lock"},
+	matches: func(p string) bool {
+		base := path.Base(p)
+		return base == "poetry.lock" || base == "Pipfile.lock"
+	},
+	parse: parsePythonLockfile,
+}
+
+// pipfileLock is the subset of Pipfile.lock (plain JSON) we care about: the
+// pinned version of every default and develop dependency.
+type pipfileLock struct {
+	Default map[string]struct {
+		Version string `json:"version"`
+	} `json:"default"`
+	Develop map[string]struct {
+		Version string `json:"version"`
+	} `json:"develop"`
+}
+
+func parsePythonLockfile(content []byte) ([]reposource.PackageDependency, error) {
+	if looksLikeJSON(content) {
+		return parsePipfileLock(content)
+	}
+	return parsePoetryLock(content)
+}
+
+// parsePoetryLock extracts the name/version pairs out of each [[package]]
+// table in a poetry.lock (TOML, read line by line the same way as
+// parseCargoLock reads Cargo.lock), e.g.:
+//
+//	[[package]]
+//	name = "requests"
+//	version = "2.28.1"
+//	description = "Python HTTP for Humans."
+func parsePoetryLock(content []byte) ([]reposource.PackageDependency, error) {
+	var deps []reposource.PackageDependency
+
+	var name, version string
+	flush := func() {
+		if name != "" && version != "" {
+			deps = append(deps, &genericDependency{scheme: "pypi", name: name, version: version})
+		}
+		name, version = "", ""
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "[[package]]":
+			flush()
+		case strings.HasPrefix(line, "name = "):
+			name = unquoteTOMLString(strings.TrimPrefix(line, "name = "))
+		case strings.HasPrefix(line, "version = "):
+			version = unquoteTOMLString(strings.TrimPrefix(line, "version = "))
+		}
+	}
+	flush()
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "invalid poetry.lock")
+	}
+	return deps, nil
+}
+
+func parsePipfileLock(content []byte) ([]reposource.PackageDependency, error) {
+	var lockfile pipfileLock
+	if err := json.Unmarshal(content, &lockfile); err != nil {
+		return nil, errors.Wrap(err, "invalid Pipfile.lock")
+	}
+
+	var deps []reposource.PackageDependency
+	for _, group := range []map[string]struct {
+		Version string `json:"version"`
+	}{lockfile.Default, lockfile.Develop} {
+		for name, pkg := range group {
+			deps = append(deps, &genericDependency{
+				scheme:  "pypi",
+				name:    name,
+				version: trimPinPrefix(pkg.Version),
+			})
+		}
+	}
+	return deps, nil
+}
+
+// trimPinPrefix strips the leading "==" that Pipfile.lock uses for exact
+// version pins (e.g. "==2.28.1" -> "2.28.1").
+func trimPinPrefix(version string) string {
+	if len(version) > 2 && version[:2] == "==" {
+		return version[2:]
+	}
+	return version
+}
+
+func looksLikeJSON(content []byte) bool {
+	for _, b := range content {
+		switch b {
+		case ' ', '\t', '\n', '\r':
+			continue
+		case '{':
+			return true
+		default:
+			return false
+		}
+	}
+	return false
+}
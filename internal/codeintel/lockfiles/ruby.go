@@ -0,0 +1,71 @@
+package lockfiles
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+)
+
+var rubyParser = parser{
+	pathspecs: []string{"**/Gemfile.lock"},
+	matches:   basenameIs("Gemfile.lock"),
+	parse:     parseGemfileLock,
+}
+
+// parseGemfileLock extracts the gems pinned under the GEM/specs section of a
+// Gemfile.lock, whose entries look like:
+//
+//	GEM
+//	  specs:
+//	    nokogiri (1.13.3)
+//	      mini_portile2 (~> 2.8.0)
+func parseGemfileLock(content []byte) ([]reposource.PackageDependency, error) {
+	var deps []reposource.PackageDependency
+
+	inSpecs := false
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case strings.TrimSpace(line) == "specs:":
+			inSpecs = true
+			continue
+		case len(line) > 0 && line[0] != ' ':
+			inSpecs = false
+		}
+
+		if !inSpecs {
+			continue
+		}
+
+		// Gem entries are indented four spaces; transitive-dependency
+		// constraints listed underneath a gem are indented six or more.
+		if !strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "      ") {
+			continue
+		}
+
+		name, version, ok := parseGemSpec(strings.TrimSpace(line))
+		if !ok {
+			continue
+		}
+
+		deps = append(deps, &genericDependency{scheme: "rubygems", name: name, version: version})
+	}
+
+	return deps, scanner.Err()
+}
+
+func parseGemSpec(line string) (name, version string, ok bool) {
+	open := strings.IndexByte(line, '(')
+	shut := strings.IndexByte(line, ')')
+	if open < 0 || shut < open {
+		return "", "", false
+	}
+
+	name = strings.TrimSpace(line[:open])
+	version = strings.TrimSpace(line[open+1 : shut])
+	return name, version, name != "" && version != ""
+}
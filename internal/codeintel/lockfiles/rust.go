@@ -0,0 +1,55 @@
+package lockfiles
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+)
+
+var rustParser = parser{
+	pathspecs: []string{"**/Cargo.lock"},
+	matches:   basenameIs("Cargo.lock"),
+	parse:     parseCargoLock,
+}
+
+// parseCargoLock extracts the name/version pairs out of each [[package]]
+// table in a Cargo.lock, e.g.:
+//
+//	[[package]]
+//	name = "serde"
+//	version = "1.0.136"
+//	source = "registry+https://github.com/rust-lang/crates.io-index"
+func parseCargoLock(content []byte) ([]reposource.PackageDependency, error) {
+	var deps []reposource.PackageDependency
+
+	var name, version string
+	flush := func() {
+		if name != "" && version != "" {
+			deps = append(deps, &genericDependency{scheme: "cargo", name: name, version: version})
+		}
+		name, version = "", ""
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "[[package]]":
+			flush()
+		case strings.HasPrefix(line, "name = "):
+			name = unquoteTOMLString(strings.TrimPrefix(line, "name = "))
+		case strings.HasPrefix(line, "version = "):
+			version = unquoteTOMLString(strings.TrimPrefix(line, "version = "))
+		}
+	}
+	flush()
+
+	return deps, scanner.Err()
+}
+
+func unquoteTOMLString(s string) string {
+	return strings.Trim(s, `"`)
+}
@@ -0,0 +1,382 @@
+// Package lockfiles resolves the package dependencies declared by lockfiles
+// committed to a repository (package-lock.json, yarn.lock, Cargo.lock, and so
+// on) without requiring the ecosystem's package manager to be installed.
+package lockfiles
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+)
+
+// GitService captures the subset of gitserver functionality needed to
+// discover and read lockfiles out of a repository at a given commit.
+type GitService interface {
+	LsFiles(ctx context.Context, repo api.RepoName, commit api.CommitID, pathspecs ...string) ([]string, error)
+	Archive(ctx context.Context, repo api.RepoName, opts gitserver.ArchiveOptions) (io.ReadCloser, error)
+	// GitLinkOID resolves the gitlink object id that git records in the
+	// tree at commit for the submodule checked out at path (the object id
+	// carried by the path's `160000 commit <oid>` tree entry).
+	GitLinkOID(ctx context.Context, repo api.RepoName, commit api.CommitID, path string) (api.CommitID, error)
+	// ReadBlobs streams the content of exactly the blobs at paths,
+	// keyed by path, instead of a full tree archive. Implementations
+	// that can't support this (older gitserver versions) should return
+	// ErrReadBlobsUnsupported so callers can fall back to Archive.
+	ReadBlobs(ctx context.Context, repo api.RepoName, commit api.CommitID, paths []string) (map[string]io.ReadCloser, error)
+}
+
+// ErrReadBlobsUnsupported is returned by GitService.ReadBlobs
+// implementations that don't support targeted blob fetches, signaling
+// Service to fall back to fetching a full archive.
+var ErrReadBlobsUnsupported = errors.New("lockfiles: ReadBlobs unsupported by this GitService")
+
+// parser recognizes and decodes one lockfile format into the package
+// dependencies it pins.
+type parser struct {
+	// pathspecs are the glob patterns used to ask LsFiles for candidate
+	// files this parser can handle.
+	pathspecs []string
+	// matches reports whether path (relative to the repository root) is a
+	// lockfile this parser understands.
+	matches func(path string) bool
+	// parse decodes the contents of a single matching file into the
+	// dependencies it declares.
+	parse func(content []byte) ([]reposource.PackageDependency, error)
+}
+
+// parsers is the set of lockfile formats Service knows how to read. Adding
+// support for a new ecosystem means appending to this slice.
+var parsers = []parser{
+	npmParser,
+	yarnParser,
+	pnpmParser,
+	rubyParser,
+	pythonParser,
+	rustParser,
+	phpParser,
+	goModParser,
+}
+
+// treeParser recognizes and decodes a lockfile format whose dependencies
+// can't be read off its contents alone, but also need information from the
+// surrounding tree (e.g. .gitmodules needs the gitlink SHA git recorded for
+// each submodule path).
+type treeParser struct {
+	pathspecs []string
+	matches   func(path string) bool
+	parse     func(ctx context.Context, gitSvc GitService, repo api.RepoName, commit api.CommitID, content []byte) ([]reposource.PackageDependency, error)
+}
+
+// treeParsers is the set of lockfile formats that need tree access, kept
+// separate from parsers so the common case doesn't pay for it.
+var treeParsers = []treeParser{
+	gitmodulesParser,
+}
+
+// pathspecs returns the LsFiles glob set assembled from every registered
+// parser, deduplicating identical patterns contributed by more than one
+// parser.
+func pathspecs() []string {
+	seen := make(map[string]struct{})
+	var out []string
+
+	add := func(pathspecs []string) {
+		for _, ps := range pathspecs {
+			if _, ok := seen[ps]; ok {
+				continue
+			}
+			seen[ps] = struct{}{}
+			out = append(out, ps)
+		}
+	}
+
+	for _, p := range parsers {
+		add(p.pathspecs)
+	}
+	for _, p := range treeParsers {
+		add(p.pathspecs)
+	}
+
+	return out
+}
+
+// parserFor returns the parser registered for path, or nil if no parser
+// recognizes it.
+func parserFor(path string) *parser {
+	for i := range parsers {
+		if parsers[i].matches(path) {
+			return &parsers[i]
+		}
+	}
+	return nil
+}
+
+// treeParserFor returns the tree parser registered for path, or nil if none
+// recognizes it.
+func treeParserFor(path string) *treeParser {
+	for i := range treeParsers {
+		if treeParsers[i].matches(path) {
+			return &treeParsers[i]
+		}
+	}
+	return nil
+}
+
+// Service resolves the package dependencies declared by lockfiles committed
+// to a repository.
+type Service struct {
+	gitSvc              GitService
+	skipVendorFiltering bool
+}
+
+// ServiceOption configures optional Service behavior.
+type ServiceOption func(*Service)
+
+// WithoutVendorFiltering disables the .gitignore/.gitattributes based
+// filtering ListDependencies otherwise applies, for callers that want the
+// raw set of lockfiles LsFiles finds, vendored or not.
+func WithoutVendorFiltering() ServiceOption {
+	return func(s *Service) {
+		s.skipVendorFiltering = true
+	}
+}
+
+func newService(gitSvc GitService, opts ...ServiceOption) *Service {
+	s := &Service{gitSvc: gitSvc}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// NewService returns a Service backed by the given GitService.
+func NewService(gitSvc GitService, opts ...ServiceOption) *Service {
+	return newService(gitSvc, opts...)
+}
+
+// TestService returns a Service for use in tests.
+func TestService(gitSvc GitService, opts ...ServiceOption) *Service {
+	return newService(gitSvc, opts...)
+}
+
+// ListDependencies returns the set of package dependencies declared by any
+// lockfile committed to repo at commit, deduplicated across nested
+// manifests (e.g. a monorepo with a package-lock.json per workspace) and,
+// unless WithoutVendorFiltering was given, excluding lockfiles that belong
+// to vendored third-party code.
+func (s *Service) ListDependencies(ctx context.Context, repo api.RepoName, commit api.CommitID) ([]reposource.PackageDependency, error) {
+	paths, err := s.gitSvc.LsFiles(ctx, repo, commit, pathspecs()...)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing lockfiles")
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	if !s.skipVendorFiltering {
+		filter, err := s.buildIgnoreFilter(ctx, repo, commit)
+		if err != nil {
+			return nil, err
+		}
+		paths = filter.filter(paths)
+		if len(paths) == 0 {
+			return nil, nil
+		}
+	}
+
+	type dependencyKey struct {
+		scheme, name, version string
+	}
+
+	seen := make(map[dependencyKey]struct{})
+	var deps []reposource.PackageDependency
+
+	addAll := func(parsed []reposource.PackageDependency) {
+		for _, dep := range parsed {
+			// Scheme is part of the key because PackageSyntax/PackageVersion
+			// alone can collide across ecosystems (e.g. a pypi and a
+			// rubygems package that happen to share a name and version).
+			key := dependencyKey{dep.Scheme(), dep.PackageSyntax(), dep.PackageVersion()}
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			deps = append(deps, dep)
+		}
+	}
+
+	err = s.forEachBlob(ctx, repo, commit, paths, func(name string, content []byte) error {
+		if p := parserFor(name); p != nil {
+			parsed, err := p.parse(content)
+			if err != nil {
+				return errors.Wrapf(err, "parsing %q", name)
+			}
+			addAll(parsed)
+			return nil
+		}
+
+		if p := treeParserFor(name); p != nil {
+			parsed, err := p.parse(ctx, s.gitSvc, repo, commit, content)
+			if err != nil {
+				return errors.Wrapf(err, "parsing %q", name)
+			}
+			addAll(parsed)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deps, nil
+}
+
+// buildIgnoreFilter discovers every .gitignore and .gitattributes committed
+// to repo at commit and compiles them into an ignoreFilter that excludes
+// vendored third-party lockfiles from the result of ListDependencies.
+func (s *Service) buildIgnoreFilter(ctx context.Context, repo api.RepoName, commit api.CommitID) (*ignoreFilter, error) {
+	paths, err := s.gitSvc.LsFiles(ctx, repo, commit, "**/.gitignore", "**/.gitattributes")
+	if err != nil {
+		return nil, errors.Wrap(err, "listing .gitignore/.gitattributes")
+	}
+	if len(paths) == 0 {
+		return &ignoreFilter{}, nil
+	}
+
+	gitignores := make(map[string][]byte)
+	gitattributes := make(map[string][]byte)
+	err = s.forEachBlob(ctx, repo, commit, paths, func(p string, content []byte) error {
+		switch path.Base(p) {
+		case ".gitignore":
+			gitignores[dirOf(p)] = content
+		case ".gitattributes":
+			gitattributes[dirOf(p)] = content
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newIgnoreFilter(gitignores, gitattributes), nil
+}
+
+// dirOf returns the directory a repository-relative path lives in ("" for
+// the repository root).
+func dirOf(filePath string) string {
+	dir := path.Dir(filePath)
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// forEachBlob fetches the content of each of paths at commit, preferring the
+// targeted GitService.ReadBlobs RPC (which avoids pulling down the rest of
+// the tree) and falling back to a full archive fetch when the GitService
+// doesn't support it, and invokes handle once per path as its content
+// becomes available.
+//
+// On the ReadBlobs path, blobs are read and handed to handle one at a time
+// rather than buffered into a map up front, so callers never hold more than
+// one lockfile's content in memory at once (the Archive fallback buffers the
+// whole archive first; see forEachBlobViaArchive). Each parser.parse still needs a single file's
+// bytes in full (the JSON/YAML decoders this package's parsers use don't
+// offer a lower-memory incremental mode), so this bounds peak memory to the
+// largest single lockfile rather than the sum of every lockfile in paths —
+// not a byte-level streaming parse of one large file.
+func (s *Service) forEachBlob(ctx context.Context, repo api.RepoName, commit api.CommitID, paths []string, handle func(path string, content []byte) error) error {
+	blobs, err := s.gitSvc.ReadBlobs(ctx, repo, commit, paths)
+	if errors.Is(err, ErrReadBlobsUnsupported) {
+		return s.forEachBlobViaArchive(ctx, repo, commit, paths, handle)
+	}
+	if err != nil {
+		return errors.Wrap(err, "reading lockfile blobs")
+	}
+
+	for _, p := range paths {
+		rc, ok := blobs[p]
+		if !ok {
+			continue
+		}
+
+		content, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return errors.Wrapf(err, "reading blob %q", p)
+		}
+		if err := handle(p, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forEachBlobViaArchive is the pre-ReadBlobs fallback: it fetches a zip
+// archive scoped to paths and reads each lockfile out of it one entry at a
+// time. This pulls down zip framing for exactly the requested paths
+// (gitserver still supports archiving a path subset), so it's strictly
+// worse than ReadBlobs only in that the archive itself must be buffered
+// whole before its entries can be read.
+func (s *Service) forEachBlobViaArchive(ctx context.Context, repo api.RepoName, commit api.CommitID, paths []string, handle func(path string, content []byte) error) error {
+	rc, err := s.gitSvc.Archive(ctx, repo, gitserver.ArchiveOptions{
+		Treeish: string(commit),
+		Format:  gitserver.ArchiveFormatZip,
+		Paths:   paths,
+	})
+	if err != nil {
+		return errors.Wrap(err, "fetching lockfile archive")
+	}
+	defer rc.Close()
+
+	archive, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return errors.Wrap(err, "reading lockfile archive")
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return errors.Wrap(err, "opening lockfile archive")
+	}
+
+	for _, f := range zr.File {
+		name := filepath.ToSlash(f.Name)
+
+		content, err := readZipFile(f)
+		if err != nil {
+			return errors.Wrapf(err, "reading %q", name)
+		}
+		if err := handle(name, content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return ioutil.ReadAll(rc)
+}
+
+// basenameIs returns a matches predicate that accepts paths whose final
+// component is exactly name, at any depth in the tree.
+func basenameIs(name string) func(string) bool {
+	return func(p string) bool {
+		return path.Base(p) == name
+	}
+}
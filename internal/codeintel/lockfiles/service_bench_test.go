@@ -0,0 +1,103 @@
+package lockfiles
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/api"
+	"github.com/sourcegraph/sourcegraph/internal/gitserver"
+)
+
+// BenchmarkListDependencies_Archive simulates the Archive fallback on a
+// repository with a large amount of non-lockfile content, against a
+// gitserver that ignores the requested paths and returns the whole tree, to
+// quantify how many bytes such an archive transfers just to read a single
+// lockfile.
+func BenchmarkListDependencies_Archive(b *testing.B) {
+	archive, lockfilePath := buildBenchArchive(b)
+
+	gitSvc := NewMockGitService()
+	gitSvc.LsFilesFunc.SetDefaultReturn([]string{lockfilePath}, nil)
+	gitSvc.ReadBlobsFunc.SetDefaultReturn(nil, ErrReadBlobsUnsupported)
+	gitSvc.ArchiveFunc.SetDefaultHook(func(c context.Context, repo api.RepoName, ao gitserver.ArchiveOptions) (io.ReadCloser, error) {
+		b.ReportMetric(float64(len(archive)), "bytes/op")
+		return io.NopCloser(bytes.NewReader(archive)), nil
+	})
+
+	s := TestService(gitSvc)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.ListDependencies(context.Background(), "foo", "HEAD"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkListDependencies_ReadBlobs simulates the targeted-fetch path on
+// the same repository: only the lockfile's own bytes are transferred.
+func BenchmarkListDependencies_ReadBlobs(b *testing.B) {
+	_, lockfilePath := buildBenchArchive(b)
+	content := []byte(`{"dependencies": { "nan": {"version": "2.15.0"} }}`)
+
+	gitSvc := NewMockGitService()
+	gitSvc.LsFilesFunc.SetDefaultReturn([]string{lockfilePath}, nil)
+	gitSvc.ReadBlobsFunc.SetDefaultHook(func(c context.Context, repo api.RepoName, commit api.CommitID, paths []string) (map[string]io.ReadCloser, error) {
+		b.ReportMetric(float64(len(content)), "bytes/op")
+		return map[string]io.ReadCloser{lockfilePath: io.NopCloser(bytes.NewReader(content))}, nil
+	})
+
+	s := TestService(gitSvc)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.ListDependencies(context.Background(), "foo", "HEAD"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// buildBenchArchive returns a zip archive containing hundreds of megabytes
+// worth of unrelated files alongside a single package-lock.json, and the
+// path of that lockfile.
+func buildBenchArchive(b *testing.B) (archive []byte, lockfilePath string) {
+	b.Helper()
+
+	const (
+		unrelatedFiles    = 200
+		bytesPerUnrelated = 1 << 20 // 1MiB each => ~200MiB of non-lockfile content
+	)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	filler := bytes.Repeat([]byte("a"), bytesPerUnrelated)
+	for i := 0; i < unrelatedFiles; i++ {
+		w, err := zw.Create(fmt.Sprintf("vendor/blob-%d.bin", i))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := w.Write(filler); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	lockfilePath = "package-lock.json"
+	w, err := zw.Create(lockfilePath)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if _, err := w.Write([]byte(`{"dependencies": { "nan": {"version": "2.15.0"} }}`)); err != nil {
+		b.Fatal(err)
+	}
+
+	if err := zw.Close(); err != nil {
+		b.Fatal(err)
+	}
+
+	return buf.Bytes(), lockfilePath
+}
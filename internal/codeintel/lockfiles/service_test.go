@@ -16,57 +16,308 @@ import (
 )
 
 func TestListDependencies(t *testing.T) {
-	gitSvc := NewMockGitService()
-	gitSvc.LsFilesFunc.SetDefaultReturn([]string{"client/package-lock.json", "package-lock.json"}, nil)
-	gitSvc.ArchiveFunc.SetDefaultHook(func(c context.Context, repo api.RepoName, ao gitserver.ArchiveOptions) (io.ReadCloser, error) {
-		var b bytes.Buffer
-		zw := zip.NewWriter(&b)
-		defer zw.Close()
-
-		for file, data := range map[string]string{
-			"client/package-lock.json": `{"dependencies": { "@octokit/request": {"version": "5.6.2"} }}`,
-			"package-lock.json":        `{"dependencies": { "nan": {"version": "2.15.0"} }}`,
-		} {
-			w, err := zw.Create(file)
-			if err != nil {
-				t.Fatal(err)
+	npmA := npmDependency(t, "@octokit/request@5.6.2")
+	npmB := npmDependency(t, "nan@2.15.0")
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []reposource.PackageDependency
+	}{
+		{
+			name: "npm",
+			files: map[string]string{
+				"client/package-lock.json": `{"dependencies": { "@octokit/request": {"version": "5.6.2"} }}`,
+				"package-lock.json":        `{"dependencies": { "nan": {"version": "2.15.0"} }}`,
+			},
+			want: []reposource.PackageDependency{npmA, npmB},
+		},
+		{
+			name: "npm dedup across nested manifests",
+			files: map[string]string{
+				"client/package-lock.json": `{"dependencies": { "nan": {"version": "2.15.0"} }}`,
+				"package-lock.json":        `{"dependencies": { "nan": {"version": "2.15.0"} }}`,
+			},
+			want: []reposource.PackageDependency{npmB},
+		},
+		{
+			name: "yarn",
+			files: map[string]string{
+				"yarn.lock": "\n" +
+					`"@octokit/request@^5.0.0":` + "\n" +
+					`  version "5.6.2"` + "\n" +
+					`  resolved "https://registry.yarnpkg.com/@octokit/request"` + "\n" +
+					"\n" +
+					`nan@^2.14.0:` + "\n" +
+					`  version "2.15.0"` + "\n",
+			},
+			want: []reposource.PackageDependency{npmA, npmB},
+		},
+		{
+			name: "pnpm",
+			files: map[string]string{
+				"pnpm-lock.yaml": "packages:\n" +
+					"  /@octokit/request/5.6.2:\n" +
+					"    resolution: {integrity: sha512-x}\n" +
+					"  /nan/2.15.0:\n" +
+					"    resolution: {integrity: sha512-y}\n",
+			},
+			want: []reposource.PackageDependency{npmA, npmB},
+		},
+		{
+			name: "ruby",
+			files: map[string]string{
+				"Gemfile.lock": "GEM\n" +
+					"  remote: https://rubygems.org/\n" +
+					"  specs:\n" +
+					"    nokogiri (1.13.3)\n" +
+					"      mini_portile2 (~> 2.8.0)\n" +
+					"    rake (13.0.6)\n",
+			},
+			want: []reposource.PackageDependency{
+				&genericDependency{scheme: "rubygems", name: "nokogiri", version: "1.13.3"},
+				&genericDependency{scheme: "rubygems", name: "rake", version: "13.0.6"},
+			},
+		},
+		{
+			name: "python poetry",
+			files: map[string]string{
+				"poetry.lock": "[[package]]\n" +
+					`name = "requests"` + "\n" +
+					`version = "2.28.1"` + "\n" +
+					"\n" +
+					"[[package]]\n" +
+					`name = "urllib3"` + "\n" +
+					`version = "1.26.9"` + "\n",
+			},
+			want: []reposource.PackageDependency{
+				&genericDependency{scheme: "pypi", name: "requests", version: "2.28.1"},
+				&genericDependency{scheme: "pypi", name: "urllib3", version: "1.26.9"},
+			},
+		},
+		{
+			name: "python pipfile",
+			files: map[string]string{
+				"Pipfile.lock": `{"default": {"requests": {"version": "==2.28.1"}}}`,
+			},
+			want: []reposource.PackageDependency{
+				&genericDependency{scheme: "pypi", name: "requests", version: "2.28.1"},
+			},
+		},
+		{
+			name: "rust",
+			files: map[string]string{
+				"Cargo.lock": "[[package]]\n" +
+					`name = "serde"` + "\n" +
+					`version = "1.0.136"` + "\n" +
+					`source = "registry+https://github.com/rust-lang/crates.io-index"` + "\n",
+			},
+			want: []reposource.PackageDependency{
+				&genericDependency{scheme: "cargo", name: "serde", version: "1.0.136"},
+			},
+		},
+		{
+			name: "php",
+			files: map[string]string{
+				"composer.lock": `{"packages": [{"name": "monolog/monolog", "version": "2.5.0"}]}`,
+			},
+			want: []reposource.PackageDependency{
+				&genericDependency{scheme: "composer", name: "monolog/monolog", version: "2.5.0"},
+			},
+		},
+		{
+			name: "go",
+			files: map[string]string{
+				"go.sum": "github.com/pkg/errors v0.9.1 h1:abc=\n" +
+					"github.com/pkg/errors v0.9.1/go.mod h1:def=\n",
+			},
+			want: []reposource.PackageDependency{
+				&genericDependency{scheme: "go", name: "github.com/pkg/errors", version: "v0.9.1"},
+			},
+		},
+		{
+			name: "git submodules",
+			files: map[string]string{
+				".gitmodules": `[submodule "vendor/lib"]` + "\n" +
+					"	path = vendor/lib\n" +
+					"	url = https://github.com/example/lib\n",
+			},
+			want: []reposource.PackageDependency{
+				NewGitSubmoduleDependency("https://github.com/example/lib", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gitSvc := NewMockGitService()
+			paths := make([]string, 0, len(test.files))
+			for p := range test.files {
+				paths = append(paths, p)
 			}
+			gitSvc.LsFilesFunc.SetDefaultReturn(paths, nil)
+			gitSvc.ArchiveFunc.SetDefaultHook(func(c context.Context, repo api.RepoName, ao gitserver.ArchiveOptions) (io.ReadCloser, error) {
+				return zipArchive(t, test.files), nil
+			})
+			gitSvc.GitLinkOIDFunc.SetDefaultHook(func(c context.Context, repo api.RepoName, commit api.CommitID, path string) (api.CommitID, error) {
+				return "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", nil
+			})
+
+			s := TestService(gitSvc)
 
-			_, err = w.Write([]byte(data))
+			got, err := s.ListDependencies(context.Background(), "foo", "HEAD")
 			if err != nil {
 				t.Fatal(err)
 			}
+
+			sortDeps(test.want)
+			sortDeps(got)
+
+			comparer := cmp.Comparer(func(a, b reposource.PackageDependency) bool {
+				return a.PackageManagerSyntax() == b.PackageManagerSyntax()
+			})
+
+			if diff := cmp.Diff(test.want, got, comparer); diff != "" {
+				t.Fatalf("dependency mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// TestListDependencies_IgnoresVendoredLockfiles asserts that a lockfile
+// excluded by .gitignore, or one tagged linguist-vendored in
+// .gitattributes, isn't reported as a dependency, and that
+// WithoutVendorFiltering restores it.
+func TestListDependencies_IgnoresVendoredLockfiles(t *testing.T) {
+	files := map[string]string{
+		".gitignore":                         "node_modules/\n",
+		"package-lock.json":                  `{"dependencies": { "nan": {"version": "2.15.0"} }}`,
+		"node_modules/foo/package-lock.json": `{"dependencies": { "ignored-by-gitignore": {"version": "1.0.0"} }}`,
+		".gitattributes":                     "vendor/** linguist-vendored\n",
+		"vendor/bar/package-lock.json":       `{"dependencies": { "ignored-by-attribute": {"version": "1.0.0"} }}`,
+	}
+
+	allPaths := make([]string, 0, len(files))
+	for p := range files {
+		allPaths = append(allPaths, p)
+	}
+
+	newGitSvc := func() *MockGitService {
+		gitSvc := NewMockGitService()
+		gitSvc.LsFilesFunc.SetDefaultHook(func(c context.Context, repo api.RepoName, commit api.CommitID, pathspecs ...string) ([]string, error) {
+			return allPaths, nil
+		})
+		gitSvc.ArchiveFunc.SetDefaultHook(func(c context.Context, repo api.RepoName, ao gitserver.ArchiveOptions) (io.ReadCloser, error) {
+			filtered := make(map[string]string, len(ao.Paths))
+			for _, p := range ao.Paths {
+				filtered[p] = files[p]
+			}
+			return zipArchive(t, filtered), nil
+		})
+		return gitSvc
+	}
+
+	t.Run("filtered by default", func(t *testing.T) {
+		s := TestService(newGitSvc())
+
+		got, err := s.ListDependencies(context.Background(), "foo", "HEAD")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []reposource.PackageDependency{npmDependency(t, "nan@2.15.0")}
+		sortDeps(got)
+
+		comparer := cmp.Comparer(func(a, b reposource.PackageDependency) bool {
+			return a.PackageManagerSyntax() == b.PackageManagerSyntax()
+		})
+		if diff := cmp.Diff(want, got, comparer); diff != "" {
+			t.Fatalf("dependency mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("WithoutVendorFiltering keeps vendored lockfiles", func(t *testing.T) {
+		s := TestService(newGitSvc(), WithoutVendorFiltering())
+
+		got, err := s.ListDependencies(context.Background(), "foo", "HEAD")
+		if err != nil {
+			t.Fatal(err)
 		}
 
-		return io.NopCloser(&b), nil
+		if len(got) != 3 {
+			t.Fatalf("want 3 dependencies with vendor filtering disabled, got %d", len(got))
+		}
+	})
+}
+
+// TestListDependencies_ReadBlobs asserts that Service prefers the targeted
+// GitService.ReadBlobs RPC over Archive when the GitService supports it,
+// and never reaches Archive at all in that case.
+func TestListDependencies_ReadBlobs(t *testing.T) {
+	files := map[string]string{
+		"package-lock.json": `{"dependencies": { "nan": {"version": "2.15.0"} }}`,
+	}
+
+	gitSvc := NewMockGitService()
+	gitSvc.LsFilesFunc.SetDefaultReturn([]string{"package-lock.json"}, nil)
+	gitSvc.ReadBlobsFunc.SetDefaultHook(func(c context.Context, repo api.RepoName, commit api.CommitID, paths []string) (map[string]io.ReadCloser, error) {
+		out := make(map[string]io.ReadCloser, len(paths))
+		for _, p := range paths {
+			out[p] = io.NopCloser(bytes.NewBufferString(files[p]))
+		}
+		return out, nil
+	})
+	gitSvc.ArchiveFunc.SetDefaultHook(func(c context.Context, repo api.RepoName, ao gitserver.ArchiveOptions) (io.ReadCloser, error) {
+		t.Fatal("Archive should not be called when ReadBlobs is supported")
+		return nil, nil
 	})
 
 	s := TestService(gitSvc)
 
-	ctx := context.Background()
-	got, err := s.ListDependencies(ctx, "foo", "HEAD")
+	got, err := s.ListDependencies(context.Background(), "foo", "HEAD")
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	want := []reposource.PackageDependency{
-		npmDependency(t, "@octokit/request@5.6.2"),
-		npmDependency(t, "nan@2.15.0"),
-	}
-
-	sort.Slice(got, func(i, j int) bool {
-		return got[i].PackageManagerSyntax() < got[j].PackageManagerSyntax()
-	})
+	want := []reposource.PackageDependency{npmDependency(t, "nan@2.15.0")}
 
 	comparer := cmp.Comparer(func(a, b reposource.PackageDependency) bool {
 		return a.PackageManagerSyntax() == b.PackageManagerSyntax()
 	})
-
 	if diff := cmp.Diff(want, got, comparer); diff != "" {
 		t.Fatalf("dependency mismatch (-want +got):\n%s", diff)
 	}
 }
 
+func zipArchive(t testing.TB, files map[string]string) io.ReadCloser {
+	t.Helper()
+
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+
+	for file, data := range files {
+		w, err := zw.Create(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return io.NopCloser(&b)
+}
+
+func sortDeps(deps []reposource.PackageDependency) {
+	sort.Slice(deps, func(i, j int) bool {
+		return deps[i].PackageManagerSyntax() < deps[j].PackageManagerSyntax()
+	})
+}
+
 func npmDependency(t testing.TB, dep string) *reposource.NPMDependency {
 	t.Helper()
 
@@ -76,4 +327,4 @@ func npmDependency(t testing.TB, dep string) *reposource.NPMDependency {
 	}
 
 	return d
-}
\ No newline at end of file
+}
@@ -0,0 +1,171 @@
+package uploadhandler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+)
+
+// ResumableUploadHandler serves the resumable multipart upload protocol:
+// clients POST framed parts (a sequence number and a SHA-256 per part) to
+// handleEnqueueMultipartResume, and can HEAD the same upload at any point
+// to learn the offset to resume from after a dropped connection, instead of
+// restarting the whole multipart upload.
+type ResumableUploadHandler struct {
+	operations  *Operations
+	persistence ResumableUploadPersistence
+	locks       *resumableUploadLocks
+}
+
+// NewResumableUploadHandler returns a ResumableUploadHandler backed by
+// persistence. A nil persistence defaults to
+// InMemoryResumableUploadPersistence.
+func NewResumableUploadHandler(operations *Operations, persistence ResumableUploadPersistence) *ResumableUploadHandler {
+	if persistence == nil {
+		persistence = NewInMemoryResumableUploadPersistence()
+	}
+
+	return &ResumableUploadHandler{
+		operations:  operations,
+		persistence: persistence,
+		locks:       newResumableUploadLocks(),
+	}
+}
+
+// ServeHTTP dispatches HEAD requests (offset discovery) and POST requests
+// (submitting a part) for the upload named by the uploadId query parameter.
+func (h *ResumableUploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	uploadID := r.URL.Query().Get("uploadId")
+	if uploadID == "" {
+		http.Error(w, "missing uploadId", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodHead:
+		h.handleResumeHead(w, r, uploadID)
+	case http.MethodPost:
+		if err := h.handleEnqueueMultipartResume(w, r, uploadID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	default:
+		w.Header().Set("Allow", "HEAD, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleResumeHead lets a client that dropped mid-upload discover where to
+// resume from, reporting the next expected part sequence and byte offset
+// for uploadID.
+func (h *ResumableUploadHandler) handleResumeHead(w http.ResponseWriter, r *http.Request, uploadID string) {
+	state, ok, err := h.persistence.Load(r.Context(), uploadID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("X-Upload-Next-Sequence", strconv.Itoa(state.NextExpectedSequence()))
+	w.Header().Set("X-Upload-Next-Offset", strconv.FormatInt(state.NextExpectedOffset(), 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleEnqueueMultipartResume accepts one framed part of a resumable
+// multipart upload, read from the X-Upload-Part-Sequence and
+// X-Upload-Part-SHA256 request headers and the request body. A request
+// that also carries X-Upload-Final-SHA256 finalizes the upload once the
+// part is accepted, rejecting the finalize if the accumulated hash doesn't
+// match.
+func (h *ResumableUploadHandler) handleEnqueueMultipartResume(w http.ResponseWriter, r *http.Request, uploadID string) (err error) {
+	ctx, _, endObservation := h.operations.handleEnqueueMultipartResume.With(r.Context(), &err, observation.Args{})
+	defer endObservation(1, observation.Args{})
+
+	seq, err := strconv.Atoi(r.Header.Get("X-Upload-Part-Sequence"))
+	if err != nil {
+		http.Error(w, "invalid or missing X-Upload-Part-Sequence", http.StatusBadRequest)
+		return nil
+	}
+
+	partSHA256, err := decodeSHA256Header(r.Header.Get("X-Upload-Part-SHA256"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+
+	content, err := io.ReadAll(r.Body)
+	if err != nil {
+		return errors.Wrap(err, "reading part body")
+	}
+
+	// Hold the per-uploadID lock across the whole load-accept-save sequence
+	// so that two pipelined or concurrently-retried parts for the same
+	// upload can't both load the same state and race on save.
+	unlock := h.locks.Lock(uploadID)
+	defer unlock()
+
+	state, ok, err := h.persistence.Load(ctx, uploadID)
+	if err != nil {
+		return errors.Wrap(err, "loading resumable upload state")
+	}
+	if !ok {
+		state = newResumableUploadState()
+	}
+
+	if err := state.AcceptPart(seq, content, partSHA256); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return nil
+	}
+
+	// Persist the accepted part before attempting to finalize, so that a
+	// hash mismatch on finalize doesn't silently roll this part back: a
+	// client retry after a 409 should never need to resend a part the
+	// server already validated.
+	if err := h.persistence.Save(ctx, uploadID, state); err != nil {
+		return errors.Wrap(err, "saving resumable upload state")
+	}
+
+	if final := r.Header.Get("X-Upload-Final-SHA256"); final != "" {
+		finalSHA256, err := decodeSHA256Header(final)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil
+		}
+
+		if err := state.Finalize(finalSHA256); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return nil
+		}
+
+		if err := h.persistence.Delete(ctx, uploadID); err != nil {
+			return errors.Wrap(err, "deleting resumable upload state")
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		return nil
+	}
+
+	w.Header().Set("X-Upload-Next-Offset", strconv.FormatInt(state.NextExpectedOffset(), 10))
+	w.WriteHeader(http.StatusAccepted)
+	return nil
+}
+
+func decodeSHA256Header(value string) ([sha256.Size]byte, error) {
+	var out [sha256.Size]byte
+
+	decoded, err := hex.DecodeString(value)
+	if err != nil || len(decoded) != sha256.Size {
+		return out, errors.New("invalid SHA-256 header value")
+	}
+
+	copy(out[:], decoded)
+	return out, nil
+}
@@ -0,0 +1,184 @@
+package uploadhandler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/internal/observation"
+)
+
+func testOperations() *Operations {
+	return NewOperations("test", &observation.TestContext)
+}
+
+func TestResumableUploadHandlerPostThenHead(t *testing.T) {
+	handler := NewResumableUploadHandler(testOperations(), nil)
+
+	part := []byte("hello world")
+	sum := sha256.Sum256(part)
+
+	req := httptest.NewRequest(http.MethodPost, "/?uploadId=upload-1", strings.NewReader(string(part)))
+	req.Header.Set("X-Upload-Part-Sequence", "0")
+	req.Header.Set("X-Upload-Part-SHA256", hex.EncodeToString(sum[:]))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("want status %d, got %d: %s", http.StatusAccepted, rec.Code, rec.Body.String())
+	}
+
+	head := httptest.NewRequest(http.MethodHead, "/?uploadId=upload-1", nil)
+	headRec := httptest.NewRecorder()
+	handler.ServeHTTP(headRec, head)
+
+	if headRec.Code != http.StatusNoContent {
+		t.Fatalf("want status %d, got %d", http.StatusNoContent, headRec.Code)
+	}
+	if got := headRec.Header().Get("X-Upload-Next-Offset"); got != "11" {
+		t.Fatalf("want next offset 11, got %q", got)
+	}
+	if got := headRec.Header().Get("X-Upload-Next-Sequence"); got != "1" {
+		t.Fatalf("want next sequence 1, got %q", got)
+	}
+}
+
+func TestResumableUploadHandlerHeadUnknownUpload(t *testing.T) {
+	handler := NewResumableUploadHandler(testOperations(), nil)
+
+	req := httptest.NewRequest(http.MethodHead, "/?uploadId=does-not-exist", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("want status %d, got %d", http.StatusNotFound, rec.Code)
+	}
+}
+
+func TestResumableUploadHandlerFinalize(t *testing.T) {
+	handler := NewResumableUploadHandler(testOperations(), nil)
+
+	part := []byte("hello world")
+	sum := sha256.Sum256(part)
+
+	req := httptest.NewRequest(http.MethodPost, "/?uploadId=upload-1", strings.NewReader(string(part)))
+	req.Header.Set("X-Upload-Part-Sequence", "0")
+	req.Header.Set("X-Upload-Part-SHA256", hex.EncodeToString(sum[:]))
+	req.Header.Set("X-Upload-Final-SHA256", hex.EncodeToString(sum[:]))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("want status %d, got %d: %s", http.StatusCreated, rec.Code, rec.Body.String())
+	}
+
+	head := httptest.NewRequest(http.MethodHead, "/?uploadId=upload-1", nil)
+	headRec := httptest.NewRecorder()
+	handler.ServeHTTP(headRec, head)
+
+	if headRec.Code != http.StatusNotFound {
+		t.Fatalf("want finalized upload state cleared, got status %d", headRec.Code)
+	}
+}
+
+// TestResumableUploadHandlerConcurrentParts asserts that two POSTs of the same
+// part racing for the same upload are serialized: exactly one is accepted and
+// the other is rejected as out of sequence, where a Load/AcceptPart/Save race
+// would otherwise accept both even though only one write survives.
+func TestResumableUploadHandlerConcurrentParts(t *testing.T) {
+	handler := NewResumableUploadHandler(testOperations(), nil)
+
+	post := func(seq int, part []byte) int {
+		sum := sha256.Sum256(part)
+
+		req := httptest.NewRequest(http.MethodPost, "/?uploadId=upload-1", strings.NewReader(string(part)))
+		req.Header.Set("X-Upload-Part-Sequence", strconv.Itoa(seq))
+		req.Header.Set("X-Upload-Part-SHA256", hex.EncodeToString(sum[:]))
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	part0 := []byte("hello ")
+	if code := post(0, part0); code != http.StatusAccepted {
+		t.Fatalf("want status %d for part 0, got %d", http.StatusAccepted, code)
+	}
+
+	// Two clients both believe part 0 landed and race to submit part 1.
+	part1 := []byte("world")
+	codes := make([]int, 2)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = post(1, part1)
+		}(i)
+	}
+	wg.Wait()
+
+	accepted, conflicted := 0, 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusAccepted:
+			accepted++
+		case http.StatusConflict:
+			conflicted++
+		default:
+			t.Fatalf("unexpected status %d for part 1", code)
+		}
+	}
+	if accepted != 1 || conflicted != 1 {
+		t.Fatalf("want exactly one part 1 POST accepted and one rejected as out-of-sequence, got accepted=%d conflicted=%d", accepted, conflicted)
+	}
+
+	head := httptest.NewRequest(http.MethodHead, "/?uploadId=upload-1", nil)
+	headRec := httptest.NewRecorder()
+	handler.ServeHTTP(headRec, head)
+
+	if got := headRec.Header().Get("X-Upload-Next-Offset"); got != strconv.Itoa(len(part0)+len(part1)) {
+		t.Fatalf("want next offset %d (both parts retained), got %q", len(part0)+len(part1), got)
+	}
+}
+
+// TestResumableUploadHandlerFinalizeMismatchPersistsAcceptedPart asserts that
+// a part accepted immediately before a failed finalize is still persisted,
+// so a client retrying after the 409 doesn't need to resend it.
+func TestResumableUploadHandlerFinalizeMismatchPersistsAcceptedPart(t *testing.T) {
+	handler := NewResumableUploadHandler(testOperations(), nil)
+
+	part := []byte("hello world")
+	sum := sha256.Sum256(part)
+	wrongFinal := sha256.Sum256([]byte("not the content"))
+
+	req := httptest.NewRequest(http.MethodPost, "/?uploadId=upload-1", strings.NewReader(string(part)))
+	req.Header.Set("X-Upload-Part-Sequence", "0")
+	req.Header.Set("X-Upload-Part-SHA256", hex.EncodeToString(sum[:]))
+	req.Header.Set("X-Upload-Final-SHA256", hex.EncodeToString(wrongFinal[:]))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("want status %d, got %d: %s", http.StatusConflict, rec.Code, rec.Body.String())
+	}
+
+	head := httptest.NewRequest(http.MethodHead, "/?uploadId=upload-1", nil)
+	headRec := httptest.NewRecorder()
+	handler.ServeHTTP(headRec, head)
+
+	if headRec.Code != http.StatusNoContent {
+		t.Fatalf("want accepted part to survive a failed finalize, got status %d", headRec.Code)
+	}
+	if got := headRec.Header().Get("X-Upload-Next-Sequence"); got != "1" {
+		t.Fatalf("want next sequence 1 (part 0 retained), got %q", got)
+	}
+}
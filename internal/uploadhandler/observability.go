@@ -13,6 +13,7 @@ type Operations struct {
 	handleEnqueueMultipartSetup    *observation.Operation
 	handleEnqueueMultipartUpload   *observation.Operation
 	handleEnqueueMultipartFinalize *observation.Operation
+	handleEnqueueMultipartResume   *observation.Operation
 }
 
 func NewOperations(prefix string, observationContext *observation.Context) *Operations {
@@ -37,5 +38,6 @@ func NewOperations(prefix string, observationContext *observation.Context) *Oper
 		handleEnqueueMultipartSetup:    op("handleEnqueueMultipartSetup"),
 		handleEnqueueMultipartUpload:   op("handleEnqueueMultipartUpload"),
 		handleEnqueueMultipartFinalize: op("handleEnqueueMultipartFinalize"),
+		handleEnqueueMultipartResume:   op("handleEnqueueMultipartResume"),
 	}
 }
@@ -0,0 +1,280 @@
+package uploadhandler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding"
+	"encoding/json"
+	"hash"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrOutOfSequence is returned by resumableUploadState.AcceptPart when a
+// part arrives whose sequence number isn't the next one expected, e.g.
+// because the client retried a part that already landed.
+var ErrOutOfSequence = errors.New("uploadhandler: part sequence number does not match the next expected part")
+
+// ErrPartHashMismatch is returned by resumableUploadState.AcceptPart when a
+// part's bytes don't match the SHA-256 the client declared for it.
+var ErrPartHashMismatch = errors.New("uploadhandler: part content does not match its declared SHA-256")
+
+// ErrFinalizeHashMismatch is returned by resumableUploadState.Finalize when
+// the accumulated hash of every part received doesn't match the final digest
+// the client declared on the finalizing request.
+var ErrFinalizeHashMismatch = errors.New("uploadhandler: accumulated upload content does not match the client-declared final digest")
+
+// resumableUploadState tracks the parts received so far for a single
+// resumable multipart upload: a monotonic sequence number and a rolling
+// SHA-256 of the bytes received. A client that drops mid-transfer can HEAD
+// the upload (see ResumableUploadHandler) to discover NextExpectedOffset
+// and resume from there instead of restarting the whole upload.
+//
+// The state round-trips through MarshalBinary/unmarshalResumableUploadState
+// so that a ResumableUploadPersistence implementation can store it between
+// requests rather than holding it in memory for the life of the upload.
+type resumableUploadState struct {
+	mu           sync.Mutex
+	nextSeq      int
+	receivedSize int64
+	rolling      hash.Hash
+}
+
+func newResumableUploadState() *resumableUploadState {
+	return &resumableUploadState{rolling: sha256.New()}
+}
+
+// NextExpectedSequence returns the part sequence number the client should
+// send next.
+func (s *resumableUploadState) NextExpectedSequence() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.nextSeq
+}
+
+// NextExpectedOffset returns the byte offset into the overall upload the
+// client should resume sending from.
+func (s *resumableUploadState) NextExpectedOffset() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.receivedSize
+}
+
+// AcceptPart verifies part seq against its declared SHA-256 and, if it's
+// the next part expected, folds its bytes into the rolling hash.
+func (s *resumableUploadState) AcceptPart(seq int, content []byte, declaredSHA256 [sha256.Size]byte) error {
+	if sha256.Sum256(content) != declaredSHA256 {
+		return ErrPartHashMismatch
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if seq != s.nextSeq {
+		return errors.Wrapf(ErrOutOfSequence, "expected part %d, got %d", s.nextSeq, seq)
+	}
+
+	// Write never returns an error for hash.Hash implementations.
+	_, _ = s.rolling.Write(content)
+	s.receivedSize += int64(len(content))
+	s.nextSeq++
+
+	return nil
+}
+
+// Finalize compares the hash of every part received so far against
+// declaredFinalSHA256, the final digest the client sent with the finalizing
+// request, and fails the finalize if they disagree.
+func (s *resumableUploadState) Finalize(declaredFinalSHA256 [sha256.Size]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var sum [sha256.Size]byte
+	copy(sum[:], s.rolling.Sum(nil))
+
+	if sum != declaredFinalSHA256 {
+		return ErrFinalizeHashMismatch
+	}
+	return nil
+}
+
+// resumableUploadStateSnapshot is the serialized form of
+// resumableUploadState: everything needed to reconstruct it, including the
+// rolling hash's own internal state (crypto/sha256's hash.Hash implements
+// encoding.BinaryMarshaler), so a part accepted on one server can be
+// resumed against on another.
+type resumableUploadStateSnapshot struct {
+	NextSeq      int    `json:"nextSeq"`
+	ReceivedSize int64  `json:"receivedSize"`
+	RollingHash  []byte `json:"rollingHash"`
+}
+
+// MarshalBinary serializes the state for storage by a
+// ResumableUploadPersistence implementation.
+func (s *resumableUploadState) MarshalBinary() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	marshaler, ok := s.rolling.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil, errors.New("uploadhandler: rolling hash does not support binary marshaling")
+	}
+
+	rollingHash, err := marshaler.MarshalBinary()
+	if err != nil {
+		return nil, errors.Wrap(err, "marshaling rolling hash")
+	}
+
+	return json.Marshal(resumableUploadStateSnapshot{
+		NextSeq:      s.nextSeq,
+		ReceivedSize: s.receivedSize,
+		RollingHash:  rollingHash,
+	})
+}
+
+// unmarshalResumableUploadState reconstructs a resumableUploadState
+// previously serialized by MarshalBinary.
+func unmarshalResumableUploadState(data []byte) (*resumableUploadState, error) {
+	var snapshot resumableUploadStateSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, errors.Wrap(err, "unmarshaling resumable upload state")
+	}
+
+	rolling := sha256.New()
+	unmarshaler, ok := rolling.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return nil, errors.New("uploadhandler: rolling hash does not support binary unmarshaling")
+	}
+	if err := unmarshaler.UnmarshalBinary(snapshot.RollingHash); err != nil {
+		return nil, errors.Wrap(err, "unmarshaling rolling hash")
+	}
+
+	return &resumableUploadState{
+		nextSeq:      snapshot.NextSeq,
+		receivedSize: snapshot.ReceivedSize,
+		rolling:      rolling,
+	}, nil
+}
+
+// resumableUploadLocks serializes the load/accept/save sequence in
+// handleEnqueueMultipartResume per uploadID. Without it, two pipelined or
+// concurrently-retried POSTs for the same upload can both Load the same
+// state, both pass AcceptPart, and then race on Save: whichever write lands
+// last silently discards the other request's accepted part even though the
+// handler already returned 202 Accepted for it. Holding this lock across the
+// whole load-accept-save(-finalize) sequence makes that sequence atomic per
+// uploadID.
+//
+// Entries are refcounted and removed as soon as no request is using them
+// (see Lock), so the map only ever holds locks for uploads with a request
+// in flight right now, not one entry per uploadID ever seen.
+type resumableUploadLocks struct {
+	mu   sync.Mutex
+	byID map[string]*resumableUploadLockEntry
+}
+
+type resumableUploadLockEntry struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func newResumableUploadLocks() *resumableUploadLocks {
+	return &resumableUploadLocks{byID: make(map[string]*resumableUploadLockEntry)}
+}
+
+// Lock locks the mutex for uploadID, creating it on first use, and returns a
+// function that unlocks it and, once no other request is waiting on the
+// same uploadID, removes its entry from byID.
+func (l *resumableUploadLocks) Lock(uploadID string) (unlock func()) {
+	l.mu.Lock()
+	e, ok := l.byID[uploadID]
+	if !ok {
+		e = &resumableUploadLockEntry{}
+		l.byID[uploadID] = e
+	}
+	e.refs++
+	l.mu.Unlock()
+
+	e.mu.Lock()
+	return func() {
+		e.mu.Unlock()
+
+		l.mu.Lock()
+		e.refs--
+		if e.refs == 0 {
+			delete(l.byID, uploadID)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// ResumableUploadPersistence durably records the bookkeeping
+// handleEnqueueMultipartResume needs to resume an upload: which parts have
+// landed and the rolling hash over their bytes. An implementation that
+// keeps this in process memory (InMemoryResumableUploadPersistence) lets a
+// client resume after a dropped connection but loses every upload's state
+// on a server restart; a durable implementation also survives a restart.
+type ResumableUploadPersistence interface {
+	Load(ctx context.Context, uploadID string) (state *resumableUploadState, ok bool, err error)
+	Save(ctx context.Context, uploadID string, state *resumableUploadState) error
+	Delete(ctx context.Context, uploadID string) error
+}
+
+// InMemoryResumableUploadPersistence is the default
+// ResumableUploadPersistence: it keeps every upload's serialized state in
+// process memory, so it does NOT survive a server restart. This is a
+// deliberate narrowing of scope for the initial cut of the resumable
+// protocol, not an oversight: resumableUploadState already round-trips
+// through MarshalBinary/unmarshalResumableUploadState specifically so that
+// swapping in a durable backend (Redis, or a table alongside the upload
+// rows this package already tracks) is a storage change behind this same
+// interface, not a redesign. Production deployments should pass a durable
+// ResumableUploadPersistence to NewResumableUploadHandler instead of
+// relying on this default.
+type InMemoryResumableUploadPersistence struct {
+	mu     sync.Mutex
+	states map[string][]byte
+}
+
+// NewInMemoryResumableUploadPersistence returns an
+// InMemoryResumableUploadPersistence.
+func NewInMemoryResumableUploadPersistence() *InMemoryResumableUploadPersistence {
+	return &InMemoryResumableUploadPersistence{states: make(map[string][]byte)}
+}
+
+func (p *InMemoryResumableUploadPersistence) Load(ctx context.Context, uploadID string) (*resumableUploadState, bool, error) {
+	p.mu.Lock()
+	data, ok := p.states[uploadID]
+	p.mu.Unlock()
+	if !ok {
+		return nil, false, nil
+	}
+
+	state, err := unmarshalResumableUploadState(data)
+	if err != nil {
+		return nil, false, err
+	}
+	return state, true, nil
+}
+
+func (p *InMemoryResumableUploadPersistence) Save(ctx context.Context, uploadID string, state *resumableUploadState) error {
+	data, err := state.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.states[uploadID] = data
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *InMemoryResumableUploadPersistence) Delete(ctx context.Context, uploadID string) error {
+	p.mu.Lock()
+	delete(p.states, uploadID)
+	p.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,185 @@
+package uploadhandler
+
+import (
+	"context"
+	"crypto/sha256"
+	"testing"
+	"time"
+)
+
+// TestResumableUploadLocksEvictsOnUnlock asserts that a resumableUploadLocks
+// entry is removed once the last holder unlocks it, so the map doesn't grow
+// by one entry for every uploadID ever seen over the life of the process.
+func TestResumableUploadLocksEvictsOnUnlock(t *testing.T) {
+	locks := newResumableUploadLocks()
+
+	unlock := locks.Lock("upload-1")
+	if n := len(locks.byID); n != 1 {
+		t.Fatalf("want 1 entry while locked, got %d", n)
+	}
+
+	unlock()
+	if n := len(locks.byID); n != 0 {
+		t.Fatalf("want 0 entries after unlock, got %d", n)
+	}
+}
+
+// TestResumableUploadLocksKeepsEntryWhileContended asserts that an entry
+// isn't evicted out from under a goroutine still waiting on it: the second
+// Lock call for the same uploadID must block until the first unlocks, not
+// race ahead on a fresh mutex for an entry deleted early.
+func TestResumableUploadLocksKeepsEntryWhileContended(t *testing.T) {
+	locks := newResumableUploadLocks()
+
+	unlock1 := locks.Lock("upload-1")
+
+	done := make(chan struct{})
+	go func() {
+		unlock2 := locks.Lock("upload-1")
+		unlock2()
+		close(done)
+	}()
+
+	// Give the goroutine a chance to reach (and block on) Lock before
+	// asserting it hasn't gotten past it yet.
+	time.Sleep(10 * time.Millisecond)
+
+	select {
+	case <-done:
+		t.Fatal("second Lock returned before the first was unlocked")
+	default:
+	}
+
+	unlock1()
+	<-done
+
+	if n := len(locks.byID); n != 0 {
+		t.Fatalf("want 0 entries once both holders are done, got %d", n)
+	}
+}
+
+func TestResumableUploadStateAcceptPart(t *testing.T) {
+	state := newResumableUploadState()
+
+	part0 := []byte("hello ")
+	part1 := []byte("world")
+
+	if err := state.AcceptPart(0, part0, sha256.Sum256(part0)); err != nil {
+		t.Fatalf("unexpected error accepting part 0: %s", err)
+	}
+	if offset := state.NextExpectedOffset(); offset != int64(len(part0)) {
+		t.Fatalf("want next offset %d, got %d", len(part0), offset)
+	}
+
+	if err := state.AcceptPart(1, part1, sha256.Sum256(part1)); err != nil {
+		t.Fatalf("unexpected error accepting part 1: %s", err)
+	}
+
+	want := sha256.Sum256(append(append([]byte{}, part0...), part1...))
+	if err := state.Finalize(want); err != nil {
+		t.Fatalf("unexpected error finalizing: %s", err)
+	}
+}
+
+func TestResumableUploadStateRejectsOutOfSequencePart(t *testing.T) {
+	state := newResumableUploadState()
+
+	part := []byte("world")
+	if err := state.AcceptPart(1, part, sha256.Sum256(part)); err == nil {
+		t.Fatal("expected error accepting out-of-sequence part, got nil")
+	}
+}
+
+func TestResumableUploadStateRejectsBadPartHash(t *testing.T) {
+	state := newResumableUploadState()
+
+	part := []byte("hello")
+	if err := state.AcceptPart(0, part, sha256.Sum256([]byte("wrong"))); err == nil {
+		t.Fatal("expected error accepting part with mismatched hash, got nil")
+	}
+}
+
+func TestResumableUploadStateRejectsBadFinalDigest(t *testing.T) {
+	state := newResumableUploadState()
+
+	part := []byte("hello")
+	if err := state.AcceptPart(0, part, sha256.Sum256(part)); err != nil {
+		t.Fatalf("unexpected error accepting part: %s", err)
+	}
+
+	if err := state.Finalize(sha256.Sum256([]byte("not the content"))); err == nil {
+		t.Fatal("expected error finalizing with mismatched digest, got nil")
+	}
+}
+
+// TestResumableUploadStateMarshalRoundTrip asserts that a state serialized
+// by MarshalBinary and restored by unmarshalResumableUploadState keeps
+// enough of the rolling hash's own state to agree on Finalize with a state
+// that never left memory, which is what lets a ResumableUploadPersistence
+// hand a part off to a different process than the one that received it.
+func TestResumableUploadStateMarshalRoundTrip(t *testing.T) {
+	state := newResumableUploadState()
+
+	part0 := []byte("hello ")
+	if err := state.AcceptPart(0, part0, sha256.Sum256(part0)); err != nil {
+		t.Fatalf("unexpected error accepting part 0: %s", err)
+	}
+
+	data, err := state.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error marshaling state: %s", err)
+	}
+
+	restored, err := unmarshalResumableUploadState(data)
+	if err != nil {
+		t.Fatalf("unexpected error unmarshaling state: %s", err)
+	}
+
+	if offset := restored.NextExpectedOffset(); offset != int64(len(part0)) {
+		t.Fatalf("want next offset %d, got %d", len(part0), offset)
+	}
+
+	part1 := []byte("world")
+	if err := restored.AcceptPart(1, part1, sha256.Sum256(part1)); err != nil {
+		t.Fatalf("unexpected error accepting part 1 on restored state: %s", err)
+	}
+
+	want := sha256.Sum256(append(append([]byte{}, part0...), part1...))
+	if err := restored.Finalize(want); err != nil {
+		t.Fatalf("unexpected error finalizing restored state: %s", err)
+	}
+}
+
+func TestInMemoryResumableUploadPersistence(t *testing.T) {
+	ctx := context.Background()
+	persistence := NewInMemoryResumableUploadPersistence()
+
+	if _, ok, err := persistence.Load(ctx, "upload-1"); err != nil || ok {
+		t.Fatalf("want no state for an unsaved upload, got ok=%v err=%v", ok, err)
+	}
+
+	state := newResumableUploadState()
+	part := []byte("hello")
+	if err := state.AcceptPart(0, part, sha256.Sum256(part)); err != nil {
+		t.Fatalf("unexpected error accepting part: %s", err)
+	}
+
+	if err := persistence.Save(ctx, "upload-1", state); err != nil {
+		t.Fatalf("unexpected error saving state: %s", err)
+	}
+
+	loaded, ok, err := persistence.Load(ctx, "upload-1")
+	if err != nil || !ok {
+		t.Fatalf("want saved state to load back, got ok=%v err=%v", ok, err)
+	}
+	if offset := loaded.NextExpectedOffset(); offset != int64(len(part)) {
+		t.Fatalf("want next offset %d, got %d", len(part), offset)
+	}
+
+	if err := persistence.Delete(ctx, "upload-1"); err != nil {
+		t.Fatalf("unexpected error deleting state: %s", err)
+	}
+	if _, ok, err := persistence.Load(ctx, "upload-1"); err != nil || ok {
+		t.Fatalf("want no state after delete, got ok=%v err=%v", ok, err)
+	}
+}